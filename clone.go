@@ -0,0 +1,152 @@
+package zfs
+
+import (
+	"bytes"
+	"strings"
+)
+
+var BOOKMARK = "bookmark"
+
+func Clone(snap, newFs string, props map[string]string) error {
+	return std.Clone(snap, newFs, props)
+}
+
+func (this *Zfs) Clone(snap, newFs string, props map[string]string) error {
+	args := []string{"clone"}
+	for k, v := range props {
+		args = append(args, "-o", k+"="+v)
+	}
+	args = append(args, snap, newFs)
+
+	c := this.Command("zfs", args...)
+	if err := c.CmdError(); err != nil {
+		return err
+	}
+	return c.Run()
+}
+
+func Promote(fs string) error {
+	return std.Promote(fs)
+}
+
+func (this *Zfs) Promote(fs string) error {
+	c := this.Command("zfs", "promote", fs)
+	if err := c.CmdError(); err != nil {
+		return err
+	}
+	return c.Run()
+}
+
+func Bookmark(snap, bookmarkName string) error {
+	return std.Bookmark(snap, bookmarkName)
+}
+
+func (this *Zfs) Bookmark(snap, bookmarkName string) error {
+	parsed, err := ParseSnap(snap)
+	if err != nil {
+		return err
+	}
+	c := this.Command("zfs", "bookmark", snap, parsed.Dataset+"#"+bookmarkName)
+	if err := c.CmdError(); err != nil {
+		return err
+	}
+	return c.Run()
+}
+
+func ListBookmarks(fs string) ([]string, error) {
+	return std.ListBookmarks(fs)
+}
+
+func (this *Zfs) ListBookmarks(fs string) ([]string, error) {
+	c := this.Command(
+		"zfs",
+		"list",
+		"-Ho",
+		"name",
+		"-t",
+		BOOKMARK,
+		"-r",
+		fs,
+	)
+	if err := c.CmdError(); err != nil {
+		return nil, err
+	}
+
+	var stdout bytes.Buffer
+	c.SetStdout(&stdout)
+	if err := c.Run(); err != nil {
+		return nil, err
+	}
+
+	out := strings.Split(stdout.String(), "\n")
+	length := len(out)
+	if length > 1 && out[length-1] == "" {
+		out = out[:length-1]
+	}
+	return out, nil
+}
+
+func Hold(snap, tag string, recursive bool) error {
+	return std.Hold(snap, tag, recursive)
+}
+
+func (this *Zfs) Hold(snap, tag string, recursive bool) error {
+	args := []string{"hold"}
+	if recursive {
+		args = append(args, "-r")
+	}
+	args = append(args, tag, snap)
+
+	c := this.Command("zfs", args...)
+	if err := c.CmdError(); err != nil {
+		return err
+	}
+	return c.Run()
+}
+
+func Release(snap, tag string, recursive bool) error {
+	return std.Release(snap, tag, recursive)
+}
+
+func (this *Zfs) Release(snap, tag string, recursive bool) error {
+	args := []string{"release"}
+	if recursive {
+		args = append(args, "-r")
+	}
+	args = append(args, tag, snap)
+
+	c := this.Command("zfs", args...)
+	if err := c.CmdError(); err != nil {
+		return err
+	}
+	return c.Run()
+}
+
+func Holds(snap string) ([]string, error) {
+	return std.Holds(snap)
+}
+
+func (this *Zfs) Holds(snap string) ([]string, error) {
+	c := this.Command("zfs", "holds", "-H", snap)
+	if err := c.CmdError(); err != nil {
+		return nil, err
+	}
+
+	var stdout bytes.Buffer
+	c.SetStdout(&stdout)
+	if err := c.Run(); err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0)
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) >= 2 {
+			out = append(out, fields[1])
+		}
+	}
+	return out, nil
+}
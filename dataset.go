@@ -0,0 +1,107 @@
+package zfs
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dataset is a single row of `zfs list`, with the common numeric and
+// time properties already parsed out of the raw tab-separated output.
+type Dataset struct {
+	Name       string
+	Type       string
+	Creation   time.Time
+	Used       uint64
+	Referenced uint64
+	Avail      uint64
+	Mountpoint string
+	Extra      map[string]string
+}
+
+var listDetailBaseProps = []string{"name", "type", "creation", "used", "refer", "avail", "mountpoint"}
+
+func ListDetail(fs, fsType string, recursive, utc bool, props []string) ([]Dataset, error) {
+	return std.ListDetail(fs, fsType, recursive, utc, props)
+}
+
+// ListDetail is like List, but returns a Dataset per row instead of a
+// bare name, parsed from a single `zfs list -Hpo ...` call so callers
+// don't have to make an extra Property() round-trip per dataset. Extra
+// property names are returned verbatim in Dataset.Extra. utc selects
+// whether Dataset.Creation is returned in UTC or local time.
+func (this *Zfs) ListDetail(fs, fsType string, recursive, utc bool, props []string) ([]Dataset, error) {
+	cols := append(append([]string{}, listDetailBaseProps...), props...)
+
+	args := []string{
+		"list",
+		"-Hpo",
+		strings.Join(cols, ","),
+		"-t",
+		fsType,
+	}
+	if recursive {
+		args = append(args, "-r")
+	}
+	if fs != "" {
+		args = append(args, fs)
+	}
+
+	c := this.Command("zfs", args...)
+	if err := c.CmdError(); err != nil {
+		return nil, err
+	}
+
+	var stdout bytes.Buffer
+	c.SetStdout(&stdout)
+	if err := c.Run(); err != nil {
+		return nil, err
+	}
+
+	var datasets []Dataset
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < len(cols) {
+			continue
+		}
+
+		d := Dataset{
+			Name:       fields[0],
+			Type:       fields[1],
+			Mountpoint: fields[6],
+		}
+
+		sec, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		d.Creation = time.Unix(sec, 0)
+		if utc {
+			d.Creation = d.Creation.UTC()
+		}
+
+		if d.Used, err = strconv.ParseUint(fields[3], 10, 64); err != nil {
+			return nil, err
+		}
+		if d.Referenced, err = strconv.ParseUint(fields[4], 10, 64); err != nil {
+			return nil, err
+		}
+		if d.Avail, err = strconv.ParseUint(fields[5], 10, 64); err != nil {
+			return nil, err
+		}
+
+		if len(props) > 0 {
+			d.Extra = make(map[string]string, len(props))
+			for i, p := range props {
+				d.Extra[p] = fields[len(listDetailBaseProps)+i]
+			}
+		}
+
+		datasets = append(datasets, d)
+	}
+	return datasets, nil
+}
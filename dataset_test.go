@@ -0,0 +1,64 @@
+package zfs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestListDetailParsesRows(t *testing.T) {
+	stdout := "pool/fs\tfilesystem\t1700000000\t1024\t2048\t4096\t/pool/fs\tvalue1\n"
+	z, _ := newFakeZfs(fakeResponse{stdout: stdout})
+
+	datasets, err := z.ListDetail("pool/fs", FS, false, true, []string{"myprop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(datasets) != 1 {
+		t.Fatalf("expected 1 dataset, got %d", len(datasets))
+	}
+
+	d := datasets[0]
+	if d.Name != "pool/fs" || d.Type != FS || d.Mountpoint != "/pool/fs" {
+		t.Fatalf("unexpected dataset: %+v", d)
+	}
+	if d.Used != 1024 || d.Referenced != 2048 || d.Avail != 4096 {
+		t.Fatalf("unexpected sizes: %+v", d)
+	}
+	if !d.Creation.Equal(time.Unix(1700000000, 0).UTC()) {
+		t.Fatalf("unexpected creation: %v", d.Creation)
+	}
+	if d.Extra["myprop"] != "value1" {
+		t.Fatalf("unexpected extra props: %+v", d.Extra)
+	}
+}
+
+func TestListDetailSkipsShortRows(t *testing.T) {
+	// A row missing the requested extra property column used to index
+	// past the end of fields; it should be skipped instead.
+	stdout := "pool/fs\tfilesystem\t1700000000\t1024\t2048\t4096\t/pool/fs\n"
+	z, _ := newFakeZfs(fakeResponse{stdout: stdout})
+
+	datasets, err := z.ListDetail("pool/fs", FS, false, false, []string{"myprop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(datasets) != 0 {
+		t.Fatalf("expected short row to be skipped, got %+v", datasets)
+	}
+}
+
+func TestListDetailLocalCreation(t *testing.T) {
+	stdout := "pool/fs\tfilesystem\t1700000000\t0\t0\t0\t/pool/fs\n"
+	z, _ := newFakeZfs(fakeResponse{stdout: stdout})
+
+	datasets, err := z.ListDetail("pool/fs", FS, false, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(datasets) != 1 {
+		t.Fatalf("expected 1 dataset, got %d", len(datasets))
+	}
+	if !datasets[0].Creation.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("unexpected creation: %v", datasets[0].Creation)
+	}
+}
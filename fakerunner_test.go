@@ -0,0 +1,63 @@
+package zfs
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/theairkit/runcmd"
+)
+
+// fakeRunner is a runcmd.Runner that hands back canned stdout for each
+// Command call instead of shelling out, so the CLI-driving methods in
+// this package can be exercised without a real zfs(8) binary. Commands
+// are matched in the order they're issued: the i'th Command call gets
+// responses[i].
+type fakeRunner struct {
+	responses []fakeResponse
+	n         int
+	commands  [][]string
+}
+
+type fakeResponse struct {
+	stdout string
+	err    error
+}
+
+func (this *fakeRunner) Command(name string, args ...string) runcmd.CmdWorker {
+	this.commands = append(this.commands, append([]string{name}, args...))
+	var resp fakeResponse
+	if this.n < len(this.responses) {
+		resp = this.responses[this.n]
+	}
+	this.n++
+	return &fakeWorker{resp: resp}
+}
+
+type fakeWorker struct {
+	resp   fakeResponse
+	stdout io.Writer
+}
+
+func (this *fakeWorker) CmdError() error { return this.resp.err }
+
+func (this *fakeWorker) Run() error {
+	if this.stdout != nil {
+		io.Copy(this.stdout, bytes.NewBufferString(this.resp.stdout))
+	}
+	return this.resp.err
+}
+
+func (this *fakeWorker) Start() error          { return this.resp.err }
+func (this *fakeWorker) Wait() error           { return nil }
+func (this *fakeWorker) SetStdout(w io.Writer) { this.stdout = w }
+func (this *fakeWorker) SetStdin(io.Reader)    {}
+func (this *fakeWorker) StdoutPipe() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewBufferString(this.resp.stdout)), nil
+}
+func (this *fakeWorker) StdinPipe() (io.WriteCloser, error) { return nil, nil }
+
+func newFakeZfs(responses ...fakeResponse) (*Zfs, *fakeRunner) {
+	r := &fakeRunner{responses: responses}
+	z, _ := NewZfs(r, nil)
+	return z, r
+}
@@ -0,0 +1,84 @@
+package zfs
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ZfsProvider is the set of operations the rest of this package's
+// higher-level tools (retention, replication, ...) need from a ZFS
+// backend. *Zfs is the real implementation, driving the zfs(8) CLI;
+// zfstest.MemZfs is an in-memory fake for unit tests.
+type ZfsProvider interface {
+	CreateSnap(fs, snap string) error
+	CreateFs(fs string) error
+	Destroy(fs string) error
+	RenameSnap(fs, snapOld, snapNew string) error
+	ExistFs(fs string) (bool, error)
+	ExistSnap(fs, snap string) (bool, error)
+	List(fs, fsType string, recursive bool) ([]string, error)
+	ListFsSnap(fs string) ([]string, error)
+	Property(fs, property string) (string, error)
+	SetProperty(fs, property, value string) error
+	RecentSnap(snap, property string) (string, error)
+	Send(fs, snapOld, snapNew string, opts SendOptions, w io.Writer) error
+	Recv(fs string, opts RecvOptions, r io.Reader) error
+	Holds(snap string) ([]string, error)
+	CreationTime(fs string) (time.Time, error)
+}
+
+var _ ZfsProvider = (*Zfs)(nil)
+
+// RecvOptions mirrors the receive-side flags that must match the
+// corresponding SendOptions a stream was produced with.
+type RecvOptions struct {
+	Force bool // -F; required for a recursive or repeated incremental recv
+}
+
+func (this *Zfs) Send(fs, snapOld, snapNew string, opts SendOptions, w io.Writer) error {
+	c := this.Command("zfs", this.sendArgs(fs, snapOld, snapNew, opts, false)...)
+	if err := c.CmdError(); err != nil {
+		return err
+	}
+	c.SetStdout(w)
+	return c.Run()
+}
+
+func (this *Zfs) Recv(fs string, opts RecvOptions, r io.Reader) error {
+	args := []string{"recv", fs}
+	if opts.Force {
+		args = []string{"recv", "-F", fs}
+	}
+
+	c := this.Command("zfs", args...)
+	if err := c.CmdError(); err != nil {
+		return err
+	}
+	c.SetStdin(r)
+	return c.Run()
+}
+
+// CreationTime returns the parsed `creation` property of fs, for
+// retention/replication code that needs to reason about snapshot age
+// through the ZfsProvider interface instead of shelling out directly.
+func (this *Zfs) CreationTime(fs string) (time.Time, error) {
+	c := this.Command("zfs", "get", "-Hpo", "value", "creation", fs)
+	if err := c.CmdError(); err != nil {
+		return time.Time{}, err
+	}
+
+	var stdout bytes.Buffer
+	c.SetStdout(&stdout)
+	if err := c.Run(); err != nil {
+		return time.Time{}, err
+	}
+
+	sec, err := strconv.ParseInt(strings.TrimSpace(stdout.String()), 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
@@ -0,0 +1,98 @@
+package zfs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSendArgs(t *testing.T) {
+	var z Zfs
+
+	full := z.sendArgs("pool/fs", "a", "", SendOptions{Recursive: true, EmbedData: true}, false)
+	want := []string{"send", "-R", "-e", "pool/fs@a"}
+	if !reflect.DeepEqual(full, want) {
+		t.Fatalf("full send: got %v, want %v", full, want)
+	}
+
+	incr := z.sendArgs("pool/fs", "a", "b", SendOptions{Compressed: true}, false)
+	want = []string{"send", "-c", "-i", "pool/fs@a", "pool/fs@b"}
+	if !reflect.DeepEqual(incr, want) {
+		t.Fatalf("incremental send: got %v, want %v", incr, want)
+	}
+
+	estimate := z.sendArgs("pool/fs", "a", "", SendOptions{}, true)
+	want = []string{"send", "-n", "-P", "pool/fs@a"}
+	if !reflect.DeepEqual(estimate, want) {
+		t.Fatalf("estimate send: got %v, want %v", estimate, want)
+	}
+}
+
+func TestEstimateSendSize(t *testing.T) {
+	z, _ := newFakeZfs(fakeResponse{stdout: "size\t123456\n"})
+
+	size, err := z.EstimateSendSize("pool/fs", "a", "", SendOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 123456 {
+		t.Fatalf("expected 123456, got %d", size)
+	}
+}
+
+func TestEstimateSendSizeNoSizeLine(t *testing.T) {
+	z, _ := newFakeZfs(fakeResponse{stdout: "unrelated output\n"})
+
+	if _, err := z.EstimateSendSize("pool/fs", "a", "", SendOptions{}); err == nil {
+		t.Fatal("expected an error when no size line is present")
+	}
+}
+
+func TestSnapName(t *testing.T) {
+	if got := snapName("pool/fs@daily-1"); got != "daily-1" {
+		t.Fatalf("expected daily-1, got %q", got)
+	}
+	if got := snapName("no-at-sign"); got != "no-at-sign" {
+		t.Fatalf("expected the input back unchanged, got %q", got)
+	}
+}
+
+// fakeProvider implements ZfsProvider with just enough behavior to
+// drive latestCommon; every other method is unused by that code path.
+type fakeProvider struct {
+	ZfsProvider
+	snaps []string
+	err   error
+}
+
+func (this *fakeProvider) ListFsSnap(fs string) ([]string, error) {
+	return this.snaps, this.err
+}
+
+func TestLatestCommon(t *testing.T) {
+	src := &fakeProvider{snaps: []string{"pool/fs@a", "pool/fs@b", "pool/fs@c"}}
+	dst := &fakeProvider{snaps: []string{"pool/fs@a", "pool/fs@b"}}
+	r := &Replicator{Src: src, Dst: dst}
+
+	got, err := r.latestCommon("pool/fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "b" {
+		t.Fatalf("expected b, got %q", got)
+	}
+}
+
+func TestLatestCommonNoneShared(t *testing.T) {
+	src := &fakeProvider{snaps: []string{"pool/fs@a"}}
+	dst := &fakeProvider{err: errors.New(DATANOE + ": pool/fs")}
+	r := &Replicator{Src: src, Dst: dst}
+
+	got, err := r.latestCommon("pool/fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "" {
+		t.Fatalf("expected no common snapshot, got %q", got)
+	}
+}
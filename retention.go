@@ -0,0 +1,168 @@
+package zfs
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy describes how many snapshots of each calendar bucket
+// to keep when pruning a filesystem tree, mirroring the usual
+// periodic-snapshot retention scheme (keep last N, plus the newest of
+// each hour/day/week/month/year).
+type RetentionPolicy struct {
+	KeepLast    int
+	KeepHourly  int
+	KeepDaily   int
+	KeepWeekly  int
+	KeepMonthly int
+	KeepYearly  int
+	MinAge      time.Duration
+	MatchPrefix string
+	HoldTag     bool
+	DryRun      bool
+}
+
+type snapInfo struct {
+	name     string
+	creation time.Time
+}
+
+// Prune applies p to the snapshots of fs, destroying everything that
+// falls outside the retention buckets (unless DryRun is set, in which
+// case destroyed only lists what would have been removed).
+func (this *Zfs) Prune(fs string, p RetentionPolicy) (kept, destroyed []string, err error) {
+	return Prune(this, fs, p)
+}
+
+// Prune is the ZfsProvider-generic form of (*Zfs).Prune, so retention
+// logic can be driven against zfstest.MemZfs in tests as well as a real
+// pool.
+func Prune(z ZfsProvider, fs string, p RetentionPolicy) (kept, destroyed []string, err error) {
+	snaps, err := snapsWithCreation(z, fs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	matched := make([]snapInfo, 0, len(snaps))
+	for _, s := range snaps {
+		if p.MatchPrefix != "" && !strings.HasPrefix(snapName(s.name), p.MatchPrefix) {
+			continue
+		}
+		matched = append(matched, s)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].creation.Before(matched[j].creation)
+	})
+
+	keep := make(map[string]bool)
+	for i := len(matched) - 1; i >= 0 && len(matched)-1-i < p.KeepLast; i-- {
+		keep[matched[i].name] = true
+	}
+
+	keepByPeriod := func(n int, bucketOf func(time.Time) string) {
+		if n <= 0 {
+			return
+		}
+		newest := make(map[string]snapInfo)
+		for _, s := range matched {
+			b := bucketOf(s.creation)
+			if cur, ok := newest[b]; !ok || s.creation.After(cur.creation) {
+				newest[b] = s
+			}
+		}
+		buckets := make([]string, 0, len(newest))
+		for b := range newest {
+			buckets = append(buckets, b)
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(buckets)))
+		if len(buckets) > n {
+			buckets = buckets[:n]
+		}
+		for _, b := range buckets {
+			keep[newest[b].name] = true
+		}
+	}
+
+	keepByPeriod(p.KeepHourly, func(t time.Time) string { return t.Format("2006010215") })
+	keepByPeriod(p.KeepDaily, func(t time.Time) string { return t.Format("20060102") })
+	keepByPeriod(p.KeepWeekly, func(t time.Time) string {
+		y, w := t.ISOWeek()
+		return strconv.Itoa(y*100 + w)
+	})
+	keepByPeriod(p.KeepMonthly, func(t time.Time) string { return t.Format("200601") })
+	keepByPeriod(p.KeepYearly, func(t time.Time) string { return t.Format("2006") })
+
+	now := time.Now()
+	for _, s := range matched {
+		if p.MinAge > 0 && now.Sub(s.creation) < p.MinAge {
+			keep[s.name] = true
+		}
+	}
+
+	for _, s := range matched {
+		if keep[s.name] {
+			kept = append(kept, s.name)
+			continue
+		}
+		if p.HoldTag {
+			holds, herr := z.Holds(s.name)
+			if herr != nil {
+				return kept, destroyed, herr
+			}
+			if len(holds) > 0 {
+				kept = append(kept, s.name)
+				continue
+			}
+		}
+		if !p.DryRun {
+			if err := z.Destroy(s.name); err != nil {
+				return kept, destroyed, err
+			}
+		}
+		destroyed = append(destroyed, s.name)
+	}
+
+	return kept, destroyed, nil
+}
+
+// snapsWithCreation lists the snapshots of fs and of every dataset below
+// it, along with their creation time. Against a real *Zfs it does this
+// with a single `zfs list -r`; against any other ZfsProvider (e.g.
+// zfstest.MemZfs) it falls back to one recursive List plus a
+// CreationTime call per snapshot, since there's no batched listing in
+// the interface.
+func snapsWithCreation(z ZfsProvider, fs string) ([]snapInfo, error) {
+	if real, ok := z.(*Zfs); ok {
+		return real.snapsWithCreation(fs)
+	}
+
+	names, err := z.List(fs, SNAP, true)
+	if err != nil {
+		return nil, err
+	}
+
+	snaps := make([]snapInfo, 0, len(names))
+	for _, name := range names {
+		creation, err := z.CreationTime(name)
+		if err != nil {
+			return nil, err
+		}
+		snaps = append(snaps, snapInfo{name: name, creation: creation})
+	}
+	return snaps, nil
+}
+
+func (this *Zfs) snapsWithCreation(fs string) ([]snapInfo, error) {
+	datasets, err := this.ListDetail(fs, SNAP, true, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	snaps := make([]snapInfo, 0, len(datasets))
+	for _, d := range datasets {
+		snaps = append(snaps, snapInfo{name: d.Name, creation: d.Creation})
+	}
+	return snaps, nil
+}
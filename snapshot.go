@@ -0,0 +1,69 @@
+package zfs
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// SnapName is a parsed "fs@snap" reference, avoiding the ad-hoc
+// fs+"@"+snap string concatenation used elsewhere in this package.
+type SnapName struct {
+	Dataset  string
+	Name     string
+	Creation time.Time
+}
+
+func (this SnapName) String() string {
+	return this.Dataset + "@" + this.Name
+}
+
+// ParseSnap splits a "fs@snap" reference into its dataset and snapshot
+// name parts.
+func ParseSnap(s string) (SnapName, error) {
+	idx := strings.Index(s, "@")
+	if idx < 0 {
+		return SnapName{}, errors.New("not a snapshot name: " + s)
+	}
+	return SnapName{Dataset: s[:idx], Name: s[idx+1:]}, nil
+}
+
+func CreateSnapRecursive(fs, snap string, props map[string]string) error {
+	return std.CreateSnapRecursive(fs, snap, props)
+}
+
+// CreateSnapRecursive snapshots fs and every descendant dataset
+// atomically, as required for a consistent recursive `send -R`.
+func (this *Zfs) CreateSnapRecursive(fs, snap string, props map[string]string) error {
+	args := []string{"snapshot", "-r"}
+	for k, v := range props {
+		args = append(args, "-o", k+"="+v)
+	}
+	args = append(args, fs+"@"+snap)
+
+	c := this.Command("zfs", args...)
+	if err := c.CmdError(); err != nil {
+		return err
+	}
+	return c.Run()
+}
+
+func CreateSnaps(specs []string) error {
+	return std.CreateSnaps(specs)
+}
+
+// CreateSnaps takes snapshots of several, possibly unrelated, datasets
+// in a single `zfs snapshot` invocation so they're all created
+// atomically with respect to each other.
+func (this *Zfs) CreateSnaps(specs []string) error {
+	if len(specs) == 0 {
+		return errors.New("no snapshots to create")
+	}
+	args := append([]string{"snapshot"}, specs...)
+
+	c := this.Command("zfs", args...)
+	if err := c.CmdError(); err != nil {
+		return err
+	}
+	return c.Run()
+}
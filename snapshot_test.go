@@ -0,0 +1,22 @@
+package zfs
+
+import "testing"
+
+func TestParseSnap(t *testing.T) {
+	got, err := ParseSnap("pool/fs@daily-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Dataset != "pool/fs" || got.Name != "daily-1" {
+		t.Fatalf("unexpected parse: %+v", got)
+	}
+	if got.String() != "pool/fs@daily-1" {
+		t.Fatalf("unexpected String(): %q", got.String())
+	}
+}
+
+func TestParseSnapNoAt(t *testing.T) {
+	if _, err := ParseSnap("pool/fs"); err == nil {
+		t.Fatal("expected an error for a reference with no '@'")
+	}
+}
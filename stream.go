@@ -0,0 +1,261 @@
+package zfs
+
+import (
+	"bufio"
+	"compress/gzip"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// StreamFilter wraps a reader with a transforming stage (compression,
+// checksumming, rate limiting, buffering, ...) that Stream stitches
+// between a send and a recv CmdWorker.
+type StreamFilter interface {
+	Wrap(io.Reader) (io.Reader, error)
+}
+
+// Stats reports how a Stream transfer went.
+type Stats struct {
+	BytesSent uint64
+	Duration  time.Duration
+}
+
+// SendSpec describes the source side of a Stream transfer.
+type SendSpec struct {
+	Fs      string
+	SnapOld string
+	SnapNew string
+	Opts    SendOptions
+}
+
+// RecvSpec describes the destination side of a Stream transfer.
+type RecvSpec struct {
+	Fs string
+}
+
+// Stream pipes a `zfs send` on this Zfs through filters, in order, into
+// a `zfs recv` on dst. Unlike SendSnap/RecvSnap, it exposes the raw
+// reader chain so callers can insert integrity checking, throttling, or
+// buffering stages between the two CmdWorkers. All filters run locally,
+// one after another, in this process: a compressing filter immediately
+// followed by its decompressing counterpart is a no-op on the wire, not
+// a way to compress data in transit (see GzipCompressFilter).
+func (this *Zfs) Stream(dst *Zfs, src SendSpec, recv RecvSpec, filters ...StreamFilter) (Stats, error) {
+	start := time.Now()
+
+	sendWorker := this.Command("zfs", this.sendArgs(src.Fs, src.SnapOld, src.SnapNew, src.Opts, false)...)
+	if err := sendWorker.CmdError(); err != nil {
+		return Stats{}, err
+	}
+	sendWorkerStdout, err := sendWorker.StdoutPipe()
+	if err != nil {
+		return Stats{}, err
+	}
+
+	recvArgs := []string{"recv", recv.Fs}
+	if src.Opts.Recursive {
+		recvArgs = []string{"recv", "-F", recv.Fs}
+	}
+	recvWorker := dst.Command("zfs", recvArgs...)
+	if err := recvWorker.CmdError(); err != nil {
+		return Stats{}, err
+	}
+	recvWorkerStdin, err := recvWorker.StdinPipe()
+	if err != nil {
+		return Stats{}, err
+	}
+
+	var r io.Reader = sendWorkerStdout
+	for _, f := range filters {
+		r, err = f.Wrap(r)
+		if err != nil {
+			return Stats{}, err
+		}
+	}
+	counter := &countingReader{r: r}
+
+	if err := recvWorker.Start(); err != nil {
+		return Stats{}, err
+	}
+	if err := sendWorker.Start(); err != nil {
+		return Stats{}, err
+	}
+
+	_, err = io.Copy(recvWorkerStdin, counter)
+	return Stats{BytesSent: counter.n, Duration: time.Since(start)}, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n uint64
+}
+
+func (this *countingReader) Read(p []byte) (int, error) {
+	n, err := this.r.Read(p)
+	this.n += uint64(n)
+	return n, err
+}
+
+// GzipCompressFilter/GzipDecompressFilter wrap a reader with gzip
+// compression/decompression. Since every filter in a Stream chain runs
+// in this same process before the bytes ever reach recvWorker's stdin,
+// using both together in one Stream call buys nothing: the stream is
+// compressed and immediately decompressed again without crossing
+// anything in between. They're meant to be used one at a time, matched
+// against whatever already-compressed or needs-compressing form the
+// data is in on either end of the actual transfer.
+type GzipCompressFilter struct{}
+
+func (GzipCompressFilter) Wrap(r io.Reader) (io.Reader, error) {
+	pr, pw := io.Pipe()
+	gw := gzip.NewWriter(pw)
+	go func() {
+		_, err := io.Copy(gw, r)
+		if err == nil {
+			err = gw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
+type GzipDecompressFilter struct{}
+
+func (GzipDecompressFilter) Wrap(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+// ZstdCompressFilter/ZstdDecompressFilter are the zstd equivalent of the
+// gzip filters above, with the same caveat: applying both in one Stream
+// call compresses and decompresses locally for no benefit, since the
+// chain runs entirely in this process ahead of recvWorker's stdin. Use
+// whichever side matches the actual form the data needs to be in.
+type ZstdCompressFilter struct{}
+
+func (ZstdCompressFilter) Wrap(r io.Reader) (io.Reader, error) {
+	pr, pw := io.Pipe()
+	zw, err := zstd.NewWriter(pw)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		_, err := io.Copy(zw, r)
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
+type ZstdDecompressFilter struct{}
+
+func (ZstdDecompressFilter) Wrap(r io.Reader) (io.Reader, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return zr.IOReadCloser(), nil
+}
+
+// Crc32Filter computes a running CRC-32C (Castagnoli) digest of the
+// bytes that pass through it without altering them. Use one instance on
+// the send side and one on the recv side, and compare Sum() once the
+// transfer finishes to verify the stream arrived intact.
+type Crc32Filter struct {
+	table *crc32.Table
+	sum   uint32
+}
+
+func NewCrc32Filter() *Crc32Filter {
+	return &Crc32Filter{table: crc32.MakeTable(crc32.Castagnoli)}
+}
+
+func (this *Crc32Filter) Wrap(r io.Reader) (io.Reader, error) {
+	return &crc32Reader{r: r, filter: this}, nil
+}
+
+func (this *Crc32Filter) Sum() uint32 {
+	return this.sum
+}
+
+type crc32Reader struct {
+	r      io.Reader
+	filter *Crc32Filter
+}
+
+func (this *crc32Reader) Read(p []byte) (int, error) {
+	n, err := this.r.Read(p)
+	if n > 0 {
+		this.filter.sum = crc32.Update(this.filter.sum, this.filter.table, p[:n])
+	}
+	return n, err
+}
+
+// ThrottleFilter limits the stream to BytesPerSec using a simple token
+// bucket, for replication jobs that must share a link with other
+// traffic.
+type ThrottleFilter struct {
+	BytesPerSec int64
+}
+
+func (this *ThrottleFilter) Wrap(r io.Reader) (io.Reader, error) {
+	if this.BytesPerSec <= 0 {
+		return r, nil
+	}
+	return &throttleReader{r: r, limit: this.BytesPerSec}, nil
+}
+
+type throttleReader struct {
+	r     io.Reader
+	limit int64
+	sent  int64
+	start time.Time
+}
+
+func (this *throttleReader) Read(p []byte) (int, error) {
+	if this.start.IsZero() {
+		this.start = time.Now()
+	}
+	if int64(len(p)) > this.limit {
+		p = p[:this.limit]
+	}
+
+	n, err := this.r.Read(p)
+	this.sent += int64(n)
+
+	elapsed := time.Since(this.start)
+	want := time.Duration(float64(this.sent) / float64(this.limit) * float64(time.Second))
+	if want > elapsed {
+		time.Sleep(want - elapsed)
+	}
+	return n, err
+}
+
+// MbufferFilter copies the stream through a bufio.Reader of Size bytes
+// (4 MiB when Size <= 0) in a separate goroutine. The goroutine hands the
+// data on through an io.Pipe, which is synchronous: it reads at most one
+// Read call's worth of the source ahead and then waits for the next
+// stage to take it. It does not keep Size bytes in reserve the way the
+// `mbuffer` tool does, so a slow destination still slows the source.
+type MbufferFilter struct {
+	Size int
+}
+
+func (this *MbufferFilter) Wrap(r io.Reader) (io.Reader, error) {
+	size := this.Size
+	if size <= 0 {
+		size = 4 << 20
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		buffered := bufio.NewReaderSize(r, size)
+		_, err := io.Copy(pw, buffered)
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
@@ -0,0 +1,108 @@
+package zfs
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestCrc32Filter(t *testing.T) {
+	f := NewCrc32Filter()
+	r, err := f.Wrap(bytes.NewBufferString("hello world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	if f.Sum() == 0 {
+		t.Fatal("expected a non-zero checksum after reading data through the filter")
+	}
+
+	f2 := NewCrc32Filter()
+	r2, _ := f2.Wrap(bytes.NewBufferString("hello world"))
+	io.Copy(io.Discard, r2)
+	if f.Sum() != f2.Sum() {
+		t.Fatalf("expected identical input to produce identical sums, got %d and %d", f.Sum(), f2.Sum())
+	}
+}
+
+func TestThrottleFilterPassesDataUnchanged(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 1024)
+	f := &ThrottleFilter{BytesPerSec: 1 << 30} // high enough that any pacing sleep is negligible
+	r, err := f.Wrap(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("ThrottleFilter altered the stream contents")
+	}
+}
+
+func TestThrottleFilterDisabled(t *testing.T) {
+	f := &ThrottleFilter{}
+	r, err := f.Wrap(bytes.NewBufferString("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == nil {
+		t.Fatal("expected Wrap to hand back a usable reader when BytesPerSec <= 0")
+	}
+}
+
+func TestMbufferFilter(t *testing.T) {
+	data := bytes.Repeat([]byte("y"), 1<<16)
+	f := &MbufferFilter{Size: 4096}
+	r, err := f.Wrap(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("MbufferFilter altered the stream contents")
+	}
+}
+
+func TestGzipFilterRoundTrip(t *testing.T) {
+	data := []byte("round trip me through gzip")
+
+	var compress GzipCompressFilter
+	cr, err := compress.Wrap(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decompress GzipDecompressFilter
+	dr, err := decompress.Wrap(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(dr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("gzip round trip mismatch: got %q, want %q", got, data)
+	}
+}
+
+func TestCountingReader(t *testing.T) {
+	cr := &countingReader{r: bytes.NewBufferString("12345")}
+	if _, err := io.ReadAll(cr); err != nil {
+		t.Fatal(err)
+	}
+	if cr.n != 5 {
+		t.Fatalf("expected to count 5 bytes, got %d", cr.n)
+	}
+}
+
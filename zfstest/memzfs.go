@@ -0,0 +1,355 @@
+// Package zfstest provides an in-memory fake of zfs.ZfsProvider, so
+// code that drives snapshot/retention/replication logic can be unit
+// tested without root or a real pool.
+package zfstest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/theairkit/zfs"
+)
+
+type dataset struct {
+	name     string
+	dsType   string
+	props    map[string]string
+	data     []byte
+	creation time.Time
+}
+
+// MemZfs is an in-memory tree of datasets, snapshots and their
+// properties, implementing zfs.ZfsProvider.
+type MemZfs struct {
+	mu       sync.Mutex
+	datasets map[string]*dataset
+	order    []string
+}
+
+func NewMemZfs() *MemZfs {
+	return &MemZfs{datasets: make(map[string]*dataset)}
+}
+
+var _ zfs.ZfsProvider = (*MemZfs)(nil)
+
+func (this *MemZfs) get(name string) (*dataset, error) {
+	ds, ok := this.datasets[name]
+	if !ok {
+		return nil, errors.New(zfs.DATANOE + ": " + name)
+	}
+	return ds, nil
+}
+
+func (this *MemZfs) CreateFs(fs string) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if _, ok := this.datasets[fs]; ok {
+		return errors.New("dataset already exists: " + fs)
+	}
+	this.datasets[fs] = &dataset{name: fs, dsType: zfs.FS, props: map[string]string{}, creation: time.Now()}
+	this.order = append(this.order, fs)
+	return nil
+}
+
+func (this *MemZfs) CreateSnap(fs, snap string) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	src, err := this.get(fs)
+	if err != nil {
+		return err
+	}
+
+	name := fs + "@" + snap
+	if _, ok := this.datasets[name]; ok {
+		return errors.New("dataset already exists: " + name)
+	}
+	this.datasets[name] = &dataset{
+		name:     name,
+		dsType:   zfs.SNAP,
+		props:    map[string]string{},
+		data:     append([]byte(nil), src.data...),
+		creation: time.Now(),
+	}
+	this.order = append(this.order, name)
+	return nil
+}
+
+func (this *MemZfs) Destroy(fs string) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if _, err := this.get(fs); err != nil {
+		return err
+	}
+
+	delete(this.datasets, fs)
+	for i, n := range this.order {
+		if n == fs {
+			this.order = append(this.order[:i], this.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (this *MemZfs) RenameSnap(fs, snapOld, snapNew string) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	oldName := fs + "@" + snapOld
+	newName := fs + "@" + snapNew
+	ds, err := this.get(oldName)
+	if err != nil {
+		return err
+	}
+
+	delete(this.datasets, oldName)
+	ds.name = newName
+	this.datasets[newName] = ds
+	for i, n := range this.order {
+		if n == oldName {
+			this.order[i] = newName
+			break
+		}
+	}
+	return nil
+}
+
+func (this *MemZfs) ExistFs(fs string) (bool, error) {
+	_, err := this.List(fs, zfs.FS, false)
+	if err != nil {
+		if strings.Contains(err.Error(), zfs.DATANOE) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (this *MemZfs) ExistSnap(fs, snap string) (bool, error) {
+	_, err := this.List(fs+"@"+snap, zfs.SNAP, false)
+	if err != nil {
+		if strings.Contains(err.Error(), zfs.DATANOE) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (this *MemZfs) List(fs, fsType string, recursive bool) ([]string, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	if strings.HasSuffix(fs, "*") {
+		prefix := strings.TrimRight(fs, "*")
+		list := make([]string, 0)
+		for _, name := range this.order {
+			ds := this.datasets[name]
+			if ds.dsType == fsType && strings.Contains(name, prefix) {
+				list = append(list, name)
+			}
+		}
+		return list, nil
+	}
+
+	if fs == "" {
+		list := make([]string, 0)
+		for _, name := range this.order {
+			if this.datasets[name].dsType == fsType {
+				list = append(list, name)
+			}
+		}
+		return list, nil
+	}
+
+	if _, err := this.get(fs); err != nil {
+		return nil, err
+	}
+
+	list := make([]string, 0)
+	for _, name := range this.order {
+		ds := this.datasets[name]
+		if ds.dsType != fsType {
+			continue
+		}
+		if name == fs || (recursive && (strings.HasPrefix(name, fs+"/") || strings.HasPrefix(name, fs+"@"))) {
+			list = append(list, name)
+		}
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+func (this *MemZfs) ListFsSnap(fs string) ([]string, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	list := make([]string, 0)
+	for _, name := range this.order {
+		ds := this.datasets[name]
+		if ds.dsType == zfs.SNAP && strings.HasPrefix(name, fs+"@") {
+			list = append(list, name)
+		}
+	}
+	return list, nil
+}
+
+func (this *MemZfs) Property(fs, property string) (string, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	ds, err := this.get(fs)
+	if err != nil {
+		return "", err
+	}
+	return ds.props[property], nil
+}
+
+func (this *MemZfs) SetProperty(fs, property, value string) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	ds, err := this.get(fs)
+	if err != nil {
+		return err
+	}
+	ds.props[property] = value
+	return nil
+}
+
+func (this *MemZfs) RecentSnap(snap, property string) (string, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	var names []string
+	for _, name := range this.order {
+		ds := this.datasets[name]
+		if ds.dsType == zfs.SNAP && strings.HasPrefix(name, snap+"@") {
+			names = append(names, name)
+		}
+	}
+
+	for i := len(names) - 1; i >= 0; i-- {
+		name := names[i]
+		if property != "" && this.datasets[name].props[property] != "true" {
+			continue
+		}
+		return name, nil
+	}
+	return "", nil
+}
+
+// Send ignores opts: MemZfs has no concept of the zfs send flags, it
+// always hands back the full contents recorded for fs@snapOld.
+func (this *MemZfs) Send(fs, snapOld, snapNew string, opts zfs.SendOptions, w io.Writer) error {
+	this.mu.Lock()
+	ds, err := this.get(fs + "@" + snapOld)
+	this.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(ds.data)
+	return err
+}
+
+// Recv ignores opts.Force: MemZfs always overwrites fs with whatever was
+// sent.
+func (this *MemZfs) Recv(fs string, opts zfs.RecvOptions, r io.Reader) error {
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		return err
+	}
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if _, ok := this.datasets[fs]; !ok {
+		this.order = append(this.order, fs)
+	}
+	this.datasets[fs] = &dataset{name: fs, dsType: zfs.FS, props: map[string]string{}, data: buf.Bytes(), creation: time.Now()}
+	return nil
+}
+
+// Hold sets a hold tag on snap, and on every descendant dataset's
+// snapshot of the same name when recursive is set, mirroring `zfs hold
+// -r`. It exists alongside Holds so HoldTag-based retention policies
+// can be exercised against MemZfs in tests.
+func (this *MemZfs) Hold(snap, tag string, recursive bool) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.setHold(snap, tag, recursive, true)
+}
+
+func (this *MemZfs) Release(snap, tag string, recursive bool) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.setHold(snap, tag, recursive, false)
+}
+
+func (this *MemZfs) setHold(snap, tag string, recursive, held bool) error {
+	ds, err := this.get(snap)
+	if err != nil {
+		return err
+	}
+
+	targets := []*dataset{ds}
+	if recursive {
+		suffix := "@" + snapName(snap)
+		prefix := strings.TrimSuffix(snap, suffix) + "/"
+		for _, name := range this.order {
+			if name == snap || !strings.HasSuffix(name, suffix) || !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			targets = append(targets, this.datasets[name])
+		}
+	}
+
+	for _, t := range targets {
+		if held {
+			t.props["hold:"+tag] = "true"
+		} else {
+			delete(t.props, "hold:"+tag)
+		}
+	}
+	return nil
+}
+
+func snapName(full string) string {
+	idx := strings.LastIndex(full, "@")
+	if idx < 0 {
+		return full
+	}
+	return full[idx+1:]
+}
+
+func (this *MemZfs) Holds(snap string) ([]string, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	ds, err := this.get(snap)
+	if err != nil {
+		return nil, err
+	}
+	holds := make([]string, 0, len(ds.props))
+	for tag := range ds.props {
+		if strings.HasPrefix(tag, "hold:") {
+			holds = append(holds, strings.TrimPrefix(tag, "hold:"))
+		}
+	}
+	return holds, nil
+}
+
+func (this *MemZfs) CreationTime(fs string) (time.Time, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	ds, err := this.get(fs)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ds.creation, nil
+}
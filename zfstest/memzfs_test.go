@@ -0,0 +1,158 @@
+package zfstest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/theairkit/zfs"
+	"github.com/theairkit/zfs/zfstest"
+)
+
+func TestReplicatorSyncWithMemZfs(t *testing.T) {
+	src := zfstest.NewMemZfs()
+	dst := zfstest.NewMemZfs()
+
+	if err := src.CreateFs("pool/fs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.CreateSnap("pool/fs", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	r := zfs.NewReplicator(src, dst)
+	if err := r.Sync("pool/fs", zfs.SendOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := dst.ExistFs("pool/fs"); err != nil || !ok {
+		t.Fatalf("expected pool/fs to exist on destination after sync, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestReplicatorSyncIncremental(t *testing.T) {
+	src := zfstest.NewMemZfs()
+	dst := zfstest.NewMemZfs()
+
+	if err := src.CreateFs("pool/fs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.CreateSnap("pool/fs", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	r := zfs.NewReplicator(src, dst)
+	if err := r.Sync("pool/fs", zfs.SendOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// MemZfs.Recv only replaces the destination filesystem, it doesn't
+	// record a matching destination snapshot; give it one directly so
+	// latestCommon has a base to find, exercising the incremental
+	// (base != "") branch of Sync rather than a repeated full send.
+	if err := dst.CreateSnap("pool/fs", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.CreateSnap("pool/fs", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Sync("pool/fs", zfs.SendOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once the destination also has "b", Sync should see base == latest
+	// and return immediately without error.
+	if err := dst.CreateSnap("pool/fs", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Sync("pool/fs", zfs.SendOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPruneWithMemZfs(t *testing.T) {
+	z := zfstest.NewMemZfs()
+	if err := z.CreateFs("pool/fs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.CreateSnap("pool/fs", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.CreateSnap("pool/fs", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	kept, destroyed, err := zfs.Prune(z, "pool/fs", zfs.RetentionPolicy{KeepLast: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 1 {
+		t.Fatalf("expected to keep 1 snapshot, got %v", kept)
+	}
+	if len(destroyed) != 1 {
+		t.Fatalf("expected to destroy 1 snapshot, got %v", destroyed)
+	}
+
+	snaps, err := z.ListFsSnap("pool/fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 1 {
+		t.Fatalf("expected MemZfs to reflect the prune, got %v", snaps)
+	}
+}
+
+func TestPruneKeepsHeldSnapshots(t *testing.T) {
+	z := zfstest.NewMemZfs()
+	if err := z.CreateFs("pool/fs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.CreateSnap("pool/fs", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.CreateSnap("pool/fs", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Hold("pool/fs@a", "keep-me", false); err != nil {
+		t.Fatal(err)
+	}
+
+	kept, destroyed, err := zfs.Prune(z, "pool/fs", zfs.RetentionPolicy{KeepLast: 1, HoldTag: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(destroyed) != 0 {
+		t.Fatalf("expected the held snapshot to survive pruning, got destroyed=%v", destroyed)
+	}
+	if len(kept) != 2 {
+		t.Fatalf("expected both snapshots to be kept (one by KeepLast, one by hold), got %v", kept)
+	}
+
+	if err := z.Release("pool/fs@a", "keep-me", false); err != nil {
+		t.Fatal(err)
+	}
+	kept, destroyed, err = zfs.Prune(z, "pool/fs", zfs.RetentionPolicy{KeepLast: 1, HoldTag: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(destroyed) != 1 || len(kept) != 1 {
+		t.Fatalf("expected the hold to no longer protect the snapshot after Release, got kept=%v destroyed=%v", kept, destroyed)
+	}
+}
+
+func TestPruneMinAgeKeepsRecentSnapshots(t *testing.T) {
+	z := zfstest.NewMemZfs()
+	if err := z.CreateFs("pool/fs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.CreateSnap("pool/fs", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	kept, destroyed, err := zfs.Prune(z, "pool/fs", zfs.RetentionPolicy{MinAge: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(destroyed) != 0 || len(kept) != 1 {
+		t.Fatalf("expected the just-created snapshot to be kept under MinAge, got kept=%v destroyed=%v", kept, destroyed)
+	}
+}